@@ -0,0 +1,66 @@
+// Package config loads the per-university settings (which spreadsheet
+// sheet to read, the scholarship name, the signature block, candidate
+// subject lines, ...) that used to be hardcoded Go constants. Forking the
+// tool for a new university is now a matter of adding an entry to
+// config.yaml instead of editing source.
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Subjects holds a university's candidate subject lines, split by mode:
+// a follow-up needs to read as a follow-up, not a first contact, so the
+// two lists can't be picked from interchangeably.
+type Subjects struct {
+	Initial  []string `yaml:"initial"`
+	Followup []string `yaml:"followup"`
+}
+
+// University holds everything about one target school that the templates
+// and the draft generator need to fill in.
+type University struct {
+	Sheet           string   `yaml:"sheet"`
+	SchoolName      string   `yaml:"schoolName"`
+	ScholarshipType string   `yaml:"scholarshipType"`
+	AttachmentPath  string   `yaml:"attachmentPath"`
+	Signature       string   `yaml:"signature"`
+	Subjects        Subjects `yaml:"subjects"`
+}
+
+// Config is the parsed contents of config.yaml: every university the
+// tool knows how to draft for, keyed by the name passed to --university.
+type Config struct {
+	Universities map[string]University `yaml:"universities"`
+}
+
+// Load reads and parses the YAML config at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// University looks up a university by the key used in config.yaml (e.g.
+// "UESTC"). If the entry doesn't set sheet explicitly, the key itself is
+// used as the spreadsheet sheet name.
+func (c *Config) University(name string) (University, error) {
+	univ, ok := c.Universities[name]
+	if !ok {
+		return University{}, fmt.Errorf("unknown university %q (check config.yaml)", name)
+	}
+	if univ.Sheet == "" {
+		univ.Sheet = name
+	}
+	return univ, nil
+}
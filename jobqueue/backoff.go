@@ -0,0 +1,26 @@
+package jobqueue
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	baseBackoff = 5 * time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+// NextRetry computes when a job should next be attempted after its
+// attempt-th failure (1-indexed): exponential backoff capped at
+// maxBackoff. If the failure carried a Retry-After delay (from a 429
+// response), that takes precedence over the computed backoff.
+func NextRetry(attempt int, retryAfter time.Duration) time.Time {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = time.Duration(math.Pow(2, float64(attempt))) * baseBackoff
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+	return time.Now().Add(delay)
+}
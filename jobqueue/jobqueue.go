@@ -0,0 +1,134 @@
+// Package jobqueue tracks per-recipient draft-generation progress in a
+// small JSON file, so a multi-hundred-row run can be interrupted
+// (Ctrl-C, a rate limit, a crash) and resumed later without silently
+// dropping rows or re-drafting ones that already went out.
+package jobqueue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// State is where a single recipient's draft is in the pipeline.
+type State string
+
+const (
+	Pending   State = "pending"
+	Generated State = "generated"
+	Drafted   State = "drafted"
+	Sent      State = "sent"
+	Failed    State = "failed"
+	Skipped   State = "skipped"
+)
+
+// Job tracks one (sheet, row, recipient) unit of work.
+type Job struct {
+	Sheet     string    `json:"sheet"`
+	Row       int       `json:"row"`
+	Recipient string    `json:"recipient"`
+	State     State     `json:"state"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	NextRetry time.Time `json:"next_retry,omitempty"`
+}
+
+func key(sheet string, row int, recipient string) string {
+	return fmt.Sprintf("%s:%d:%s", sheet, row, recipient)
+}
+
+// Store is a JSON-file-backed set of Jobs, keyed by (sheet, row,
+// recipient).
+type Store struct {
+	path string
+	jobs map[string]*Job
+}
+
+// New returns an empty Store that will save to path, ignoring any run
+// recorded there previously.
+func New(path string) *Store {
+	return &Store{path: path, jobs: map[string]*Job{}}
+}
+
+// Open loads the Store previously saved at path. A missing file is not
+// an error: it just means there's no prior run to resume.
+func Open(path string) (*Store, error) {
+	s := New(path)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, j := range jobs {
+		s.jobs[key(j.Sheet, j.Row, j.Recipient)] = j
+	}
+	return s, nil
+}
+
+// Save writes the store back to its path.
+func (s *Store) Save() error {
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal jobs: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Ensure returns the existing job for (sheet, row, recipient), creating
+// a new Pending one if this is the first time it's been seen.
+func (s *Store) Ensure(sheet string, row int, recipient string) *Job {
+	k := key(sheet, row, recipient)
+	if j, ok := s.jobs[k]; ok {
+		return j
+	}
+	j := &Job{Sheet: sheet, Row: row, Recipient: recipient, State: Pending}
+	s.jobs[k] = j
+	return j
+}
+
+// Due returns jobs that should be attempted now: every Pending job, plus
+// Failed jobs whose backoff has elapsed. includeAllFailed (--retry-failed)
+// ignores the backoff and returns every Failed job too. The result is
+// sorted by row so that truncating it to a batch size is deterministic
+// across runs, rather than depending on Go's randomized map iteration
+// order.
+func (s *Store) Due(now time.Time, includeAllFailed bool) []*Job {
+	var due []*Job
+	for _, j := range s.jobs {
+		switch j.State {
+		case Pending:
+			due = append(due, j)
+		case Failed:
+			if includeAllFailed || !j.NextRetry.After(now) {
+				due = append(due, j)
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Row < due[j].Row })
+	return due
+}
+
+// Summary counts jobs per state, for the end-of-run report.
+func (s *Store) Summary() map[State]int {
+	counts := map[State]int{}
+	for _, j := range s.jobs {
+		counts[j.State]++
+	}
+	return counts
+}
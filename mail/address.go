@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+// Recipient is a display name plus an email address, e.g. from a "Name:
+// email@host" cell in the spreadsheet. Name may be empty.
+type Recipient struct {
+	Name  string
+	Email string
+}
+
+// atomText matches RFC 5322's atext (the characters a "dot-atom" display
+// name may use without quoting).
+var atomText = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+\-/=?^_` + "`" + `{|}~ ]+$`)
+
+// controlChars matches CR, LF and other control characters a spreadsheet
+// cell has no legitimate reason to contain. Left in place, a CR/LF in a
+// display name would terminate the header line early and let the rest
+// of the "value" forge arbitrary headers (e.g. a Bcc) into the message,
+// so it's stripped before name or email ever reaches a header.
+var controlChars = regexp.MustCompile(`[\x00-\x1F\x7F]`)
+
+// formatAddress renders an RFC 5322 mailbox for name/email, so a display
+// name containing commas, quotes, or non-ASCII characters can't break the
+// header or get mangled by Gmail. Non-ASCII names are MIME encoded-word
+// wrapped; ASCII names that aren't valid atoms are quoted instead.
+func formatAddress(name, email string) string {
+	name = strings.TrimSpace(controlChars.ReplaceAllString(name, ""))
+	email = controlChars.ReplaceAllString(email, "")
+	if name == "" {
+		return fmt.Sprintf("<%s>", email)
+	}
+
+	if !isASCII(name) {
+		return fmt.Sprintf("%s <%s>", mime.BEncoding.Encode("utf-8", name), email)
+	}
+
+	if atomText.MatchString(name) {
+		return fmt.Sprintf("%s <%s>", name, email)
+	}
+
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name)
+	return fmt.Sprintf(`"%s" <%s>`, quoted, email)
+}
+
+// formatAddressList renders each recipient as an RFC 5322 mailbox.
+func formatAddressList(recipients []Recipient) []string {
+	formatted := make([]string, len(recipients))
+	for i, r := range recipients {
+		formatted[i] = formatAddress(r.Name, r.Email)
+	}
+	return formatted
+}
+
+// Address renders a single RFC 5322 mailbox for name/email. It's exported
+// for callers (e.g. the mailgun transport) that need a formatted address
+// outside of a Message's own headers.
+func Address(name, email string) string {
+	return formatAddress(name, email)
+}
+
+// AddressList renders recipients as a single comma-separated RFC 5322
+// address list.
+func AddressList(recipients []Recipient) string {
+	return strings.Join(formatAddressList(recipients), ", ")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > 127 {
+			return false
+		}
+	}
+	return true
+}
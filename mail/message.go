@@ -0,0 +1,302 @@
+// Package mail builds MIME email messages: plain/HTML alternatives,
+// inline (CID-referenced) images, and attachments, nested the way real
+// mail clients expect (multipart/mixed > multipart/related >
+// multipart/alternative). The API is modeled after gomail v2
+// (github.com/go-gomail/gomail) so it should feel familiar to anyone
+// who has used that library, but it only depends on the standard
+// library.
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// part is one alternative body (text/plain, text/html, ...).
+type part struct {
+	contentType string
+	body        string
+}
+
+// file is an attachment or an embedded (inline) file.
+type file struct {
+	name        string
+	path        string
+	contentType string
+	cid         string // set for embeds, used as the "cid:" reference
+}
+
+// FileSetting customizes an attached or embedded file; see Rename and
+// ContentType.
+type FileSetting func(*file)
+
+// Rename overrides the filename reported to the recipient, which
+// otherwise defaults to filepath.Base(path).
+func Rename(name string) FileSetting {
+	return func(f *file) { f.name = name }
+}
+
+// ContentType overrides the attachment's MIME type, which otherwise is
+// guessed from the file extension.
+func ContentType(contentType string) FileSetting {
+	return func(f *file) { f.contentType = contentType }
+}
+
+// Message is a MIME email under construction. The zero value is not
+// usable; create one with NewMessage.
+type Message struct {
+	header      textproto.MIMEHeader
+	headerOrder []string
+	parts       []part
+	attachments []file
+	embeds      []file
+}
+
+// NewMessage returns an empty Message ready for SetHeader/SetBody/etc.
+func NewMessage() *Message {
+	return &Message{header: textproto.MIMEHeader{}}
+}
+
+// SetHeader sets a raw header field, such as "Subject", to one or more
+// values (multiple values are joined with ", ", as for a recipient
+// list). Use SetAddressHeader for a single From/Reply-To mailbox so the
+// display name is escaped correctly.
+func (m *Message) SetHeader(field string, values ...string) {
+	if _, exists := m.header[field]; !exists {
+		m.headerOrder = append(m.headerOrder, field)
+	}
+	m.header[field] = values
+}
+
+// SetAddressHeader sets a header field to a single "Name <address>"
+// mailbox, RFC 5322 encoded.
+func (m *Message) SetAddressHeader(field, name, address string) {
+	m.SetHeader(field, formatAddress(name, address))
+}
+
+// SetAddressListHeader sets a header field (To, Cc, Bcc) to a
+// comma-separated list of RFC 5322 mailboxes.
+func (m *Message) SetAddressListHeader(field string, recipients []Recipient) {
+	if len(recipients) == 0 {
+		return
+	}
+	m.SetHeader(field, formatAddressList(recipients)...)
+}
+
+// SetBody sets the message's sole body part, replacing any existing
+// parts. Most callers with both a text and HTML body should call
+// AddAlternative twice instead.
+func (m *Message) SetBody(contentType, body string) {
+	m.parts = []part{{contentType: contentType, body: body}}
+}
+
+// AddAlternative adds another representation of the same body (e.g.
+// text/plain alongside text/html). Parts are wrapped in a
+// multipart/alternative in the order added, so put the plainest
+// representation first.
+func (m *Message) AddAlternative(contentType, body string) {
+	m.parts = append(m.parts, part{contentType: contentType, body: body})
+}
+
+// Attach adds a file the recipient downloads separately.
+func (m *Message) Attach(path string, settings ...FileSetting) {
+	m.attachments = append(m.attachments, newFile(path, settings))
+}
+
+// Embed adds an inline file (e.g. a signature logo) and returns its
+// Content-ID, so an HTML body can reference it via
+// src="cid:<the returned id>".
+func (m *Message) Embed(path string, settings ...FileSetting) string {
+	f := newFile(path, settings)
+	f.cid = newContentID()
+	m.embeds = append(m.embeds, f)
+	return f.cid
+}
+
+func newFile(path string, settings []FileSetting) file {
+	f := file{path: path, name: filepath.Base(path)}
+	for _, s := range settings {
+		s(&f)
+	}
+	if f.contentType == "" {
+		f.contentType = mime.TypeByExtension(filepath.Ext(f.name))
+		if f.contentType == "" {
+			f.contentType = "application/octet-stream"
+		}
+	}
+	return f
+}
+
+func newContentID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf) + "@local"
+}
+
+// WriteTo renders the message as an RFC 5322 document and writes it to
+// w, so the same Message can be handed to any transport: a Gmail draft,
+// an SMTP DATA command, or a file on disk.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	for _, field := range m.headerOrder {
+		writeHeader(bw, field, m.header[field])
+	}
+	bw.WriteString("MIME-Version: 1.0\r\n")
+
+	altBuf, altBoundary, err := m.writeAlternative()
+	if err != nil {
+		return cw.n, err
+	}
+	bodyBuf, bodyContentType := altBuf, "multipart/alternative; boundary="+altBoundary
+
+	if len(m.embeds) > 0 {
+		bodyBuf, bodyContentType, err = wrapMultipart("related", bodyBuf.Bytes(), bodyContentType, m.embeds, false)
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	if len(m.attachments) > 0 {
+		bodyBuf, bodyContentType, err = wrapMultipart("mixed", bodyBuf.Bytes(), bodyContentType, m.attachments, true)
+		if err != nil {
+			return cw.n, err
+		}
+	}
+
+	bw.WriteString("Content-Type: " + bodyContentType + "\r\n\r\n")
+	bw.Write(bodyBuf.Bytes())
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// writeAlternative renders m.parts as a multipart/alternative body.
+func (m *Message) writeAlternative() (*bytes.Buffer, string, error) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+
+	for _, p := range m.parts {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		qpw := quotedprintable.NewWriter(pw)
+		if _, err := qpw.Write([]byte(p.body)); err != nil {
+			return nil, "", err
+		}
+		if err := qpw.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf, mw.Boundary(), nil
+}
+
+// wrapMultipart wraps innerBody (already-built MIME content of type
+// innerContentType) in a new multipart/<kind> along with files, each
+// placed as its own part. Embeds get a Content-ID and inline
+// disposition; attachments get a plain attachment disposition.
+func wrapMultipart(kind string, innerBody []byte, innerContentType string, files []file, asAttachment bool) (*bytes.Buffer, string, error) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+
+	innerPart, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {innerContentType}})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := innerPart.Write(innerBody); err != nil {
+		return nil, "", err
+	}
+
+	for _, f := range files {
+		if err := writeFilePart(mw, f, asAttachment); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf, "multipart/" + kind + "; boundary=" + mw.Boundary(), nil
+}
+
+func writeFilePart(mw *multipart.Writer, f file, asAttachment bool) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", f.path, err)
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("%s; name=%q", f.contentType, f.name)},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if asAttachment {
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.name))
+	} else {
+		header.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", f.name))
+		header.Set("Content-ID", "<"+f.cid+">")
+	}
+
+	pw, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(pw, foldBase64(data))
+	return err
+}
+
+// foldBase64 base64-encodes data and folds it to 76-character lines, as
+// RFC 2045 requires for the base64 Content-Transfer-Encoding.
+func foldBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	const lineLen = 76
+	var out strings.Builder
+	for i := 0; i < len(encoded); i += lineLen {
+		end := i + lineLen
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		out.WriteString(encoded[i:end])
+		out.WriteString("\r\n")
+	}
+	return out.String()
+}
+
+func writeHeader(w io.Writer, field string, values []string) {
+	fmt.Fprintf(w, "%s: %s\r\n", field, strings.Join(values, ", "))
+}
+
+// countingWriter tracks bytes written so WriteTo can satisfy io.WriterTo's
+// (int64, error) signature without buffering the whole message twice.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	anchorTagRe    = regexp.MustCompile(`(?is)<a\s+[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	lineBreakTagRe = regexp.MustCompile(`(?i)<br\s*/?>`)
+	paragraphEndRe = regexp.MustCompile(`(?i)</p\s*>`)
+	anyTagRe       = regexp.MustCompile(`(?s)<[^>]+>`)
+	blankRunRe     = regexp.MustCompile(`\n{3,}`)
+)
+
+// SetHTMLBody sets the message's body to htmlBody, alongside a plain-text
+// alternative derived from it, so mail clients that don't render HTML
+// (aerc, mutt, and the like) still see something readable.
+func (m *Message) SetHTMLBody(htmlBody string) {
+	m.AddAlternative("text/plain", PlainText(htmlBody))
+	m.AddAlternative("text/html", htmlBody)
+}
+
+// PlainText derives a plain-text rendering of an HTML email body, for
+// callers (e.g. the mailgun transport) that build their own message
+// outside of a Message's multipart/alternative body.
+func PlainText(htmlBody string) string {
+	return htmlToText(htmlBody)
+}
+
+// htmlToText strips tags from a small, known-shape HTML email body:
+// links become "text (url)", <br>/<p> become newlines, and entities are
+// decoded. It is not a general-purpose HTML-to-text converter.
+func htmlToText(body string) string {
+	text := anchorTagRe.ReplaceAllString(body, "$2 ($1)")
+	text = lineBreakTagRe.ReplaceAllString(text, "\n")
+	text = paragraphEndRe.ReplaceAllString(text, "\n\n")
+	text = anyTagRe.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+	text = blankRunRe.ReplaceAllString(text, "\n\n")
+
+	return strings.TrimSpace(text)
+}
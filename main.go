@@ -2,77 +2,98 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
-	"mime"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sashabaranov/go-openai"
 	"github.com/xuri/excelize/v2"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/gmail/v1"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/config"
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/jobqueue"
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/mail"
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/render"
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/transport"
 )
 
 const (
-	excelPath         = "professors.xlsx"
-	scholarshipType   = "CSC Scholarship"
-	followUpSheetName = "ZJU"
-	//followUpSchoolName = "Zhejiang University"
-	sheetName       = "UESTC"
-	schoolName      = "University of Electronic Science and Technology of China (UESTC)"
-	attachmentPath  = "UsamaShoukatCV.pdf"
-	credentialsFile = "credentials.json"
-	tokenFile       = "token.json"
-	openAIModel     = "gpt-4o-mini"
-	maxDrafts       = 30
+	excelPath    = "professors.xlsx"
+	configPath   = "config.yaml"
+	templatesDir = "templates"
+	openAIModel  = "gpt-4o-mini"
+	maxDrafts    = 30
+	senderName   = "Usama Shoukat"
 )
 
+// researchPlan is the applicant's research-interest paragraph. It's not
+// university-specific, so unlike schoolName/scholarshipType it stays a
+// constant rather than a config.yaml field.
+const researchPlan = `
+My intended research plan involves exploring distributed systems and backend technologies using Go,
+with an emphasis on building efficient, reliable, and scalable software systems.
+I am also interested in integrating AI-based optimization or automation approaches into software engineering problems.
+`
+
 func main() {
+	university := flag.String("university", "", "university key from config.yaml (e.g. UESTC)")
+	mode := flag.String("mode", "initial", "email template to use: initial or followup")
+	fresh := flag.Bool("fresh", false, "wipe the existing job store and start this run from scratch")
+	retryFailed := flag.Bool("retry-failed", false, "retry failed rows immediately, ignoring their backoff timer")
+	dryRun := flag.Bool("dry-run", false, "print what would be processed without calling OpenAI or the mail transport")
+	flag.Parse()
+
 	log.Println("Starting email draft generator...")
-	err := godotenv.Load()
-	if err != nil {
+	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
-	openaiKey := os.Getenv("OPENAI_API_KEY")
-	if openaiKey == "" {
-		log.Fatal("OPENAI_API_KEY not set")
+	if *university == "" {
+		log.Fatal("--university is required (see config.yaml for available keys)")
+	}
+	if *mode != "initial" && *mode != "followup" {
+		log.Fatalf("unknown --mode %q: must be initial or followup", *mode)
 	}
 
-	mode := "initial"
-	if len(os.Args) > 1 && (os.Args[1] == "followup" || os.Args[1] == "initial") {
-		mode = os.Args[1]
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	univ, err := cfg.University(*university)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	log.Printf("Mode: %s", strings.ToUpper(mode))
+	log.Printf("University: %s | Mode: %s", *university, strings.ToUpper(*mode))
 
-	if err := generateDrafts(mode); err != nil {
+	if err := generateDrafts(univ, *mode, *fresh, *retryFailed, *dryRun); err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
 	log.Println("✅ Done! Check your Gmail Drafts folder.")
 }
 
-func generateDrafts(mode string) error {
+// recipientRow is the data a spreadsheet row needs to generate and draft
+// an email; it's kept separate from jobqueue.Job because the job store
+// only needs to remember *state*, not the row's content.
+type recipientRow struct {
+	name, email, research string
+	cc, bcc               []mail.Recipient
+}
+
+func generateDrafts(univ config.University, mode string, fresh, retryFailed, dryRun bool) error {
 	f, err := excelize.OpenFile(excelPath)
 	if err != nil {
 		return fmt.Errorf("open excel: %w", err)
 	}
 
-	sheet := sheetName
-	if mode == "followup" {
-		sheet = followUpSheetName
-	}
-
-	rows, err := f.GetRows(sheet)
+	rows, err := f.GetRows(univ.Sheet)
 	if err != nil {
 		return fmt.Errorf("read rows: %w", err)
 	}
@@ -81,45 +102,32 @@ func generateDrafts(mode string) error {
 		return fmt.Errorf("no data rows found")
 	}
 
-	progressFile := sheet + "Progress.txt"
-	if mode == "followup" {
-		progressFile = "followUpProgress.txt"
-	}
-
-	startIndex := 1
-	if data, err := os.ReadFile(progressFile); err == nil {
-		var savedIndex int
-		if _, err := fmt.Sscanf(string(data), "%d", &savedIndex); err == nil {
-			startIndex = savedIndex
-		}
-	}
-
-	endIndex := startIndex + maxDrafts
-	log.Printf("Processing professors %d to %d ...", startIndex, endIndex)
-
-	// Gmail + OpenAI setup
-	gSrv, err := getGmailService()
+	tmpl, err := render.Load(templatesDir)
 	if err != nil {
-		return fmt.Errorf("gmail auth: %w", err)
+		return fmt.Errorf("load templates: %w", err)
 	}
 
-	openAIKey := os.Getenv("OPENAI_API_KEY")
-	if openAIKey == "" {
-		return fmt.Errorf("OPENAI_API_KEY not set")
+	// A university's initial and follow-up passes track separate
+	// progress, so sending a follow-up doesn't find every row already
+	// Drafted from the initial pass and have nothing left to do.
+	storePath := fmt.Sprintf("%s-%s-jobs.json", univ.Sheet, mode)
+
+	// Resuming is the default: the store is keyed on (sheet, row,
+	// recipient), so re-running an ordinary invocation is naturally
+	// idempotent. --fresh opts into wiping prior state instead.
+	var store *jobqueue.Store
+	if fresh {
+		store = jobqueue.New(storePath)
+	} else {
+		store, err = jobqueue.Open(storePath)
+		if err != nil {
+			return fmt.Errorf("open job store: %w", err)
+		}
 	}
-	aiClient := openai.NewClient(openAIKey)
-	ctx := context.Background()
 
-	count := 0
+	rowData := map[int]recipientRow{}
 	for i, row := range rows {
-		if i < startIndex {
-			continue
-		}
-		if i >= endIndex {
-			break
-		}
-
-		if len(row) < 3 {
+		if i == 0 || len(row) < 3 {
 			continue
 		}
 
@@ -140,225 +148,249 @@ func generateDrafts(mode string) error {
 			continue
 		}
 
-		subject, body, err := generateEmail(ctx, aiClient, name, research, mode)
-		if err != nil {
-			log.Printf("❌ Email generation failed for %s: %v", name, err)
-			continue
+		var cc, bcc []mail.Recipient
+		if len(row) > 3 {
+			cc = parseRecipients(row[3])
 		}
-
-		if err := createDraft(gSrv, email, subject, body, attachmentPath); err != nil {
-			log.Printf("❌ Draft creation failed for %s: %v", name, err)
-		} else {
-			log.Printf("✅ Draft created for %s <%s>", name, email)
-			count++
+		if len(row) > 4 {
+			bcc = parseRecipients(row[4])
 		}
 
-		time.Sleep(2 * time.Second)
+		store.Ensure(univ.Sheet, i, email)
+		rowData[i] = recipientRow{name: name, email: email, research: research, cc: cc, bcc: bcc}
 	}
 
-	newProgress := endIndex
-	if newProgress > len(rows) {
-		newProgress = len(rows)
+	due := store.Due(time.Now(), retryFailed)
+	if len(due) > maxDrafts {
+		log.Printf("%d job(s) due; processing the first %d this run", len(due), maxDrafts)
+		due = due[:maxDrafts]
+	} else {
+		log.Printf("%d job(s) due (pending or ready for retry)", len(due))
 	}
-	os.WriteFile(progressFile, []byte(fmt.Sprintf("%d", newProgress)), 0644)
 
-	log.Printf("Total drafts created: %d", count)
-	log.Printf("Progress saved: next run will start from row %d", newProgress)
-	return nil
-}
+	if dryRun {
+		for _, job := range due {
+			data := rowData[job.Row]
+			log.Printf("[dry-run] would process row %d %s <%s> (state=%s, attempts=%d)", job.Row, data.name, job.Recipient, job.State, job.Attempts)
+		}
+		printSummary(store.Summary())
+		return nil
+	}
 
-func generateEmail(ctx context.Context, client *openai.Client, profName, researchText, mode string) (string, string, error) {
-	prompt := fmt.Sprintf(`
-Extract 1–2 main research topics or directions from the professor’s research text below (keep it short and clear, no sentences).
-Professor's research text:
-%s
-`, researchText)
+	mailer, err := transport.New()
+	if err != nil {
+		return fmt.Errorf("mail transport: %w", err)
+	}
 
-	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: openAIModel,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: "system", Content: "You are a concise academic assistant summarizing professors’ research fields in a few words."},
-			{Role: "user", Content: prompt},
-		},
-		Temperature: 0.4,
-	})
-	if err != nil || len(resp.Choices) == 0 {
-		log.Printf("⚠️ OpenAI topic extraction failed for %s: %v", profName, err)
-		return "", "", err
+	openAIKey := os.Getenv("OPENAI_API_KEY")
+	if openAIKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY not set")
 	}
+	aiClient := openai.NewClient(openAIKey)
+	ctx := context.Background()
 
-	researchTopics := strings.TrimSpace(resp.Choices[0].Message.Content)
-	if researchTopics == "" {
-		researchTopics = "computer science and related technologies"
+	concurrency := 1
+	if v := os.Getenv("JOB_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			concurrency = n
+		}
 	}
 
-	researchPlan := `
-My intended research plan involves exploring distributed systems and backend technologies using Go,
-with an emphasis on building efficient, reliable, and scalable software systems.
-I am also interested in integrating AI-based optimization or automation approaches into software engineering problems.
-`
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, job := range due {
+		data, ok := rowData[job.Row]
+		if !ok {
+			continue
+		}
 
-	intro := fmt.Sprintf(`
-Respected Professor %s,<br><br>
-I hope you are doing well.`, profName)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(job *jobqueue.Job, data recipientRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			processJob(ctx, aiClient, mailer, tmpl, univ, mode, job, data, &mu)
+
+			// Save after every job so an interrupted run keeps a record of
+			// the rows it already drafted.
+			mu.Lock()
+			if err := store.Save(); err != nil {
+				log.Printf("save job store: %v", err)
+			}
+			mu.Unlock()
+
+			if concurrency == 1 {
+				time.Sleep(2 * time.Second)
+			}
+		}(job, data)
+	}
+	wg.Wait()
 
-	if mode == "followup" {
-		intro = fmt.Sprintf(`
-Respected Professor %s,<br><br>
-I hope you are doing well. I wanted to kindly follow up on my previous email regarding the possibility of pursuing a Master's degree under your supervision.`, profName)
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("save job store: %w", err)
 	}
 
-	subject := getRandomSubject()
-	body := fmt.Sprintf(`
-<html>
-<body>
-%s<br><br>
+	printSummary(store.Summary())
+	return nil
+}
 
-I am <b>Usama Shoukat</b> from Pakistan, and I have completed my Bachelor's in Computer Science
-with a CGPA of <b>3.51/4.00</b> from Government College University Faisalabad, a well-known institution in Pakistan.<br><br>
+// processJob runs one row through generation and drafting, updating job
+// under mu so concurrent workers don't race on its state.
+func processJob(ctx context.Context, aiClient *openai.Client, mailer transport.Transport, tmpl *render.Templates, univ config.University, mode string, job *jobqueue.Job, data recipientRow, mu *sync.Mutex) {
+	mu.Lock()
+	job.Attempts++
+	mu.Unlock()
 
-I came across your research profile and was deeply impressed by your work in <b>%s</b>.
-I find your research directions highly relevant to my academic background and interests.<br><br>
+	subject, body, err := generateEmail(ctx, aiClient, tmpl, univ, data.name, data.research, mode)
+	if err != nil {
+		recordFailure(mu, job, classifyOpenAIError(err))
+		return
+	}
 
-%s<br><br>
+	mu.Lock()
+	job.State = jobqueue.Generated
+	mu.Unlock()
+
+	msg := transport.Message{
+		To:             mail.Recipient{Name: data.name, Email: data.email},
+		Cc:             data.cc,
+		Bcc:            data.bcc,
+		Subject:        subject,
+		HTMLBody:       body,
+		AttachmentPath: univ.AttachmentPath,
+	}
+	if err := mailer.Draft(ctx, msg); err != nil {
+		recordFailure(mu, job, err)
+		return
+	}
 
-I am highly motivated to pursue a Master's degree under your supervision and intend to apply for the <b>%s</b>
-(or any equivalent scholarship offered by your institution).<br><br>
+	mu.Lock()
+	job.State = jobqueue.Drafted
+	job.LastError = ""
+	mu.Unlock()
+	log.Printf("✅ Draft created for %s <%s>", data.name, data.email)
+}
 
-If you find my profile suitable, it would be an honor to discuss the possibility of joining your research group.
-I have attached my CV for your review.<br><br>
+// recordFailure marks job Failed and schedules its next retry, honoring
+// any Retry-After the failure carried.
+func recordFailure(mu *sync.Mutex, job *jobqueue.Job, err error) {
+	mu.Lock()
+	defer mu.Unlock()
 
-Thank you very much for your time and consideration.<br><br>
+	job.State = jobqueue.Failed
+	job.LastError = err.Error()
 
-Best regards,<br>
-<b>Usama Shoukat</b><br>
-WeChat ID: UsamaShoukatCS<br>
-</body>
-</html>
-`, intro, researchTopics, researchPlan, scholarshipType)
+	var retryable *transport.RetryableError
+	var retryAfter time.Duration
+	if errors.As(err, &retryable) {
+		retryAfter = retryable.RetryAfter
+	}
+	job.NextRetry = jobqueue.NextRetry(job.Attempts, retryAfter)
 
-	return subject, body, nil
+	log.Printf("❌ row %d <%s> failed (attempt %d): %v", job.Row, job.Recipient, job.Attempts, err)
 }
 
-func getRandomSubject() string {
-	subjects := []string{
-		"Request for Master's Supervision (September 2026 Intake)",
-		"Prospective Master's Student Interested in Your Research (2026 Intake)",
-		"Supervision Inquiry for Master's Program (Fall 2026)",
-		"Seeking Master's Supervision at Your Research Group (2026)",
-		"Application for Master's Supervision - September 2026",
-		"Interest in Joining Your Research Group for Master's 2026",
-		"Inquiry Regarding Master's Supervision (2026 Admission)",
-		"Exploring Master's Research Opportunities with You (2026)",
-		"Request to Pursue Master's Studies Under Your Guidance (2026)",
-		"Potential Master's Student Interested in Your Research Work",
-	}
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	return subjects[r.Intn(len(subjects))]
+// classifyOpenAIError wraps a 429/5xx OpenAI API error in a
+// transport.RetryableError so it backs off the same way a Gmail rate
+// limit does.
+func classifyOpenAIError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && (apiErr.HTTPStatusCode == 429 || apiErr.HTTPStatusCode >= 500) {
+		return &transport.RetryableError{Err: err}
+	}
+	return err
 }
 
-func getGmailService() (*gmail.Service, error) {
-	ctx := context.Background()
-	b, err := os.ReadFile(credentialsFile)
+func printSummary(counts map[jobqueue.State]int) {
+	log.Printf("Summary: pending=%d generated=%d drafted=%d sent=%d failed=%d skipped=%d",
+		counts[jobqueue.Pending], counts[jobqueue.Generated], counts[jobqueue.Drafted],
+		counts[jobqueue.Sent], counts[jobqueue.Failed], counts[jobqueue.Skipped])
+}
+
+// generateEmail extracts the professor's research topics via OpenAI, then
+// renders the subject and body through the university's templates.
+func generateEmail(ctx context.Context, client *openai.Client, tmpl *render.Templates, univ config.University, profName, researchText, mode string) (string, string, error) {
+	topics, err := extractResearchTopics(ctx, client, profName, researchText)
 	if err != nil {
-		return nil, fmt.Errorf("read credentials.json: %w", err)
+		return "", "", err
 	}
-	config, err := google.ConfigFromJSON(b, gmail.GmailComposeScope, gmail.GmailModifyScope)
-	if err != nil {
-		return nil, fmt.Errorf("config parse: %w", err)
+
+	data := render.Data{
+		Professor:         render.Person{Name: profName},
+		University:        univ,
+		ResearchTopics:    topics,
+		ResearchParagraph: researchPlan,
+		Sender:            render.Person{Name: senderName},
+		Mode:              mode,
 	}
 
-	tok, err := tokenFromFile(tokenFile)
+	subject, err := tmpl.Subject(data)
 	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(tokenFile, tok)
+		return "", "", err
 	}
-	client := config.Client(ctx, tok)
-	return gmail.New(client)
-}
-
-func createDraft(srv *gmail.Service, to, subject, body, attachmentFile string) error {
-	rawMsg, err := buildRawEmail(to, subject, body, attachmentFile)
+	body, err := tmpl.Body(mode, data)
 	if err != nil {
-		return fmt.Errorf("build email: %w", err)
+		return "", "", err
 	}
-	msg := &gmail.Message{Raw: rawMsg}
-	_, err = srv.Users.Drafts.Create("me", &gmail.Draft{Message: msg}).Do()
-	return err
+	return subject, body, nil
 }
 
-func buildRawEmail(to, subject, plainBody, attachmentFile string) (string, error) {
-	boundary := "BOUNDARY123"
-	var msg strings.Builder
-
-	msg.WriteString(fmt.Sprintf("To: %s\r\n", to))
-	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
-	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n", boundary))
-	msg.WriteString("\r\n")
-
-	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	msg.WriteString("Content-Type: text/html; charset=utf-8\r\n\r\n")
-	msg.WriteString(plainBody + "\r\n")
+// extractResearchTopics asks OpenAI to summarize a professor's research
+// text into a short phrase suitable for dropping into an email.
+func extractResearchTopics(ctx context.Context, client *openai.Client, profName, researchText string) (string, error) {
+	prompt := fmt.Sprintf(`
+Extract 1–2 main research topics or directions from the professor’s research text below (keep it short and clear, no sentences).
+Professor's research text:
+%s
+`, researchText)
 
-	data, err := os.ReadFile(attachmentFile)
+	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openAIModel,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: "system", Content: "You are a concise academic assistant summarizing professors’ research fields in a few words."},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.4,
+	})
 	if err != nil {
+		log.Printf("⚠️ OpenAI topic extraction failed for %s: %v", profName, err)
 		return "", err
 	}
-	_, fileName := filepath.Split(attachmentFile)
-	mimeType := mime.TypeByExtension(filepath.Ext(fileName))
-	if mimeType == "" {
-		mimeType = "application/octet-stream"
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned")
 	}
 
-	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	msg.WriteString(fmt.Sprintf("Content-Type: %s; name=\"%s\"\r\n", mimeType, fileName))
-	msg.WriteString("Content-Transfer-Encoding: base64\r\n")
-	msg.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=\"%s\"\r\n\r\n", fileName))
-
-	encoded := base64.StdEncoding.EncodeToString(data)
-	for i := 0; i < len(encoded); i += 76 {
-		end := i + 76
-		if end > len(encoded) {
-			end = len(encoded)
-		}
-		msg.WriteString(encoded[i:end] + "\r\n")
+	topics := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if topics == "" {
+		topics = "computer science and related technologies"
 	}
-	msg.WriteString(fmt.Sprintf("--%s--", boundary))
-
-	return base64.URLEncoding.EncodeToString([]byte(msg.String())), nil
-}
-
-func tokenFromFile(path string) (*oauth2.Token, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
+	return topics, nil
 }
 
-func saveToken(path string, token *oauth2.Token) {
-	f, err := os.Create(path)
-	if err != nil {
-		log.Fatalf("Unable to save oauth token: %v", err)
+// parseRecipients parses a Cc/Bcc spreadsheet cell containing one or more
+// comma-separated "Name:email@host" pairs, the same shorthand used by the
+// profInfo column.
+func parseRecipients(field string) []mail.Recipient {
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil
 	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
-	log.Printf("Token saved to %s", path)
-}
 
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
-	fmt.Printf("Go to the following URL and paste the authorization code:\n\n%s\n\n", authURL)
-	fmt.Print("Enter code: ")
-	var code string
-	fmt.Scan(&code)
-	tok, err := config.Exchange(context.Background(), strings.TrimSpace(code))
-	if err != nil {
-		log.Fatalf("Token exchange error: %v", err)
+	var recipients []mail.Recipient
+	for _, entry := range strings.Split(field, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		email := strings.TrimSpace(parts[1])
+		if email == "" {
+			continue
+		}
+		recipients = append(recipients, mail.Recipient{Name: name, Email: email})
 	}
-	return tok
+	return recipients
 }
@@ -0,0 +1,105 @@
+// Package render fills in the templates/ directory's .tmpl files with a
+// professor's details, so the email's wording lives in text files a fork
+// can edit instead of in Go source.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/config"
+)
+
+// Person is a name a template can address formally or by first name.
+type Person struct {
+	Name string
+}
+
+// FirstName returns the first whitespace-separated token of Name, for
+// templates that want to address someone less formally.
+func (p Person) FirstName() string {
+	if fields := strings.Fields(p.Name); len(fields) > 0 {
+		return fields[0]
+	}
+	return p.Name
+}
+
+// Data is everything a body or subject template can reference.
+type Data struct {
+	Professor         Person
+	University        config.University
+	ResearchTopics    string
+	ResearchParagraph string
+	Sender            Person
+	// Mode is "initial" or "followup", so subject.tmpl can pick from the
+	// matching half of University.Subjects.
+	Mode string
+}
+
+// subjectFuncs are available inside subject.tmpl.
+var subjectFuncs = texttemplate.FuncMap{
+	"pick": func(options []string) string {
+		if len(options) == 0 {
+			return ""
+		}
+		return options[rand.Intn(len(options))]
+	},
+}
+
+// bodyFuncs are available inside the "*.html.tmpl" body templates.
+var bodyFuncs = template.FuncMap{
+	// nl2br renders a signature block (or any config string) written as
+	// plain-text lines in config.yaml as proper HTML line breaks.
+	"nl2br": func(s string) template.HTML {
+		return template.HTML(strings.ReplaceAll(template.HTMLEscapeString(s), "\n", "<br>\n"))
+	},
+}
+
+// Templates holds the parsed body and subject templates loaded from a
+// templates directory.
+type Templates struct {
+	bodies  *template.Template
+	subject *texttemplate.Template
+}
+
+// Load parses every "*.html.tmpl" file in dir as a body template (named
+// by its filename, e.g. "initial.html.tmpl") plus dir/subject.tmpl for
+// the subject line.
+func Load(dir string) (*Templates, error) {
+	bodies, err := template.New(filepath.Base(dir)).Funcs(bodyFuncs).ParseGlob(filepath.Join(dir, "*.html.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("parse body templates: %w", err)
+	}
+
+	subject, err := texttemplate.New("subject.tmpl").Funcs(subjectFuncs).ParseFiles(filepath.Join(dir, "subject.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("parse subject template: %w", err)
+	}
+
+	return &Templates{bodies: bodies, subject: subject}, nil
+}
+
+// Body renders the "<mode>.html.tmpl" template (mode is "initial" or
+// "followup") with data.
+func (t *Templates) Body(mode string, data Data) (string, error) {
+	name := mode + ".html.tmpl"
+	var buf bytes.Buffer
+	if err := t.bodies.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Subject renders subject.tmpl with data.
+func (t *Templates) Subject(data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.subject.ExecuteTemplate(&buf, "subject.tmpl", data); err != nil {
+		return "", fmt.Errorf("render subject: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const devOutputDir = "dev-mail-out"
+
+// DevTransport dumps the rendered MIME message to a file under
+// dev-mail-out/ instead of contacting Gmail, SMTP, or Mailgun, so the
+// rest of the pipeline can be exercised without real credentials.
+type DevTransport struct{}
+
+// NewDevTransport creates dev-mail-out/ if it doesn't already exist.
+func NewDevTransport() (*DevTransport, error) {
+	if err := os.MkdirAll(devOutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", devOutputDir, err)
+	}
+	return &DevTransport{}, nil
+}
+
+func (t *DevTransport) Draft(ctx context.Context, msg Message) error {
+	return t.dump(msg, "draft")
+}
+
+func (t *DevTransport) Send(ctx context.Context, msg Message) error {
+	return t.dump(msg, "sent")
+}
+
+func (t *DevTransport) dump(msg Message, action string) error {
+	raw, err := buildRawEmail(msg, true)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	data, err := decodeRawEmail(raw)
+	if err != nil {
+		return fmt.Errorf("decode email: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%s-%s.eml", time.Now().UnixNano(), action, sanitizeFilename(msg.To.Email))
+	path := filepath.Join(devOutputDir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	fmt.Printf("[dev transport] %s -> %s\n", action, path)
+	return nil
+}
+
+func sanitizeFilename(s string) string {
+	r := []rune(s)
+	for i, c := range r {
+		if c == '/' || c == '\\' || c == ':' || c == ' ' {
+			r[i] = '_'
+		}
+	}
+	return string(r)
+}
@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+const (
+	credentialsFile = "credentials.json"
+	tokenFile       = "token.json"
+)
+
+// GmailTransport drafts (and optionally sends) mail through the Gmail API.
+// This is the tool's original delivery path; the message itself is built
+// by buildRawEmail, as for the other raw-MIME transports.
+type GmailTransport struct {
+	srv *gmail.Service
+}
+
+// NewGmailTransport authenticates against the Gmail API, prompting for an
+// OAuth code on first run and caching the resulting token in tokenFile.
+func NewGmailTransport() (*GmailTransport, error) {
+	srv, err := getGmailService()
+	if err != nil {
+		return nil, fmt.Errorf("gmail auth: %w", err)
+	}
+	return &GmailTransport{srv: srv}, nil
+}
+
+func (t *GmailTransport) Draft(ctx context.Context, msg Message) error {
+	rawMsg, err := buildRawEmail(msg, true)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	gMsg := &gmail.Message{Raw: rawMsg}
+	_, err = t.srv.Users.Drafts.Create("me", &gmail.Draft{Message: gMsg}).Do()
+	return classifyError(err)
+}
+
+func (t *GmailTransport) Send(ctx context.Context, msg Message) error {
+	rawMsg, err := buildRawEmail(msg, true)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	_, err = t.srv.Users.Messages.Send("me", &gmail.Message{Raw: rawMsg}).Do()
+	return classifyError(err)
+}
+
+func getGmailService() (*gmail.Service, error) {
+	ctx := context.Background()
+	b, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read credentials.json: %w", err)
+	}
+	config, err := google.ConfigFromJSON(b, gmail.GmailComposeScope, gmail.GmailModifyScope)
+	if err != nil {
+		return nil, fmt.Errorf("config parse: %w", err)
+	}
+
+	tok, err := tokenFromFile(tokenFile)
+	if err != nil {
+		tok, err = getTokenFromWeb(config)
+		if err != nil {
+			return nil, fmt.Errorf("oauth exchange: %w", err)
+		}
+		if err := saveToken(tokenFile, tok); err != nil {
+			return nil, fmt.Errorf("save token: %w", err)
+		}
+	}
+	client := config.Client(ctx, tok)
+	return gmail.New(client)
+}
+
+func tokenFromFile(path string) (*oauth2.Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+func saveToken(path string, token *oauth2.Token) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(token)
+}
+
+func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	fmt.Printf("Go to the following URL and paste the authorization code:\n\n%s\n\n", authURL)
+	fmt.Print("Enter code: ")
+	var code string
+	fmt.Scan(&code)
+	return config.Exchange(context.Background(), strings.TrimSpace(code))
+}
@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/mail"
+)
+
+const mailgunAPIBase = "https://api.mailgun.net/v3"
+
+// MailgunTransport sends mail through the Mailgun HTTP API. Like SMTP, it
+// has no draft concept.
+type MailgunTransport struct {
+	apiKey string
+	domain string
+	from   string
+	client *http.Client
+}
+
+// NewMailgunTransport reads MAILGUN_API_KEY, MAILGUN_DOMAIN and
+// MAILGUN_FROM from the environment.
+func NewMailgunTransport() (*MailgunTransport, error) {
+	apiKey := os.Getenv("MAILGUN_API_KEY")
+	domain := os.Getenv("MAILGUN_DOMAIN")
+	from := os.Getenv("MAILGUN_FROM")
+	if apiKey == "" || domain == "" || from == "" {
+		return nil, errors.New("MAILGUN_API_KEY, MAILGUN_DOMAIN and MAILGUN_FROM must all be set")
+	}
+	return &MailgunTransport{apiKey: apiKey, domain: domain, from: from, client: http.DefaultClient}, nil
+}
+
+func (t *MailgunTransport) Draft(ctx context.Context, msg Message) error {
+	return errors.New("mailgun transport has no draft concept; use Send or MAIL_TRANSPORT=gmail")
+}
+
+func (t *MailgunTransport) Send(ctx context.Context, msg Message) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"from":    t.from,
+		"to":      mail.Address(msg.To.Name, msg.To.Email),
+		"subject": msg.Subject,
+		"html":    msg.HTMLBody,
+		"text":    mail.PlainText(msg.HTMLBody),
+	}
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return fmt.Errorf("write field %s: %w", k, err)
+		}
+	}
+	if len(msg.Cc) > 0 {
+		if err := w.WriteField("cc", mail.AddressList(msg.Cc)); err != nil {
+			return fmt.Errorf("write field cc: %w", err)
+		}
+	}
+	if len(msg.Bcc) > 0 {
+		if err := w.WriteField("bcc", mail.AddressList(msg.Bcc)); err != nil {
+			return fmt.Errorf("write field bcc: %w", err)
+		}
+	}
+
+	if msg.AttachmentPath != "" {
+		data, err := os.ReadFile(msg.AttachmentPath)
+		if err != nil {
+			return fmt.Errorf("read attachment: %w", err)
+		}
+		part, err := w.CreateFormFile("attachment", filepath.Base(msg.AttachmentPath))
+		if err != nil {
+			return fmt.Errorf("create attachment part: %w", err)
+		}
+		if _, err := part.Write(data); err != nil {
+			return fmt.Errorf("write attachment: %w", err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close multipart body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/messages", mailgunAPIBase, t.domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailgun request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("mailgun returned %s: %s", resp.Status, respBody)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return &RetryableError{Err: err, RetryAfter: retryAfterFromHeader(resp.Header)}
+		}
+		return err
+	}
+	return nil
+}
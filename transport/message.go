@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/mail"
+)
+
+// buildRawEmail renders m as a base64url-encoded RFC 2822 message, using
+// the mail package to build the underlying MIME structure. includeBcc
+// controls whether a Bcc header is written: Gmail strips it server-side
+// before delivery, but a real SMTP relay would happily mail it out
+// verbatim, so SMTP sends pass false and rely on extra RCPT TOs instead.
+func buildRawEmail(m Message, includeBcc bool) (string, error) {
+	fromName, fromAddress, err := fromHeader()
+	if err != nil {
+		return "", err
+	}
+
+	msg := mail.NewMessage()
+	msg.SetAddressHeader("From", fromName, fromAddress)
+	msg.SetAddressHeader("To", m.To.Name, m.To.Email)
+	msg.SetAddressListHeader("Cc", m.Cc)
+	if includeBcc {
+		msg.SetAddressListHeader("Bcc", m.Bcc)
+	}
+	msg.SetHeader("Subject", m.Subject)
+	msg.SetHTMLBody(m.HTMLBody)
+	if m.AttachmentPath != "" {
+		msg.Attach(m.AttachmentPath)
+	}
+
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return "", fmt.Errorf("render message: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// fromHeader reads the sender identity that buildRawEmail writes into the
+// From header (the Gmail, SMTP and dev transports; Mailgun uses
+// MAILGUN_FROM instead). MAIL_FROM_NAME is optional; MAIL_FROM_ADDRESS is required
+// so a message is never sent without a mandatory RFC 5322 From.
+func fromHeader() (name, address string, err error) {
+	address = os.Getenv("MAIL_FROM_ADDRESS")
+	if address == "" {
+		return "", "", fmt.Errorf("MAIL_FROM_ADDRESS must be set")
+	}
+	return os.Getenv("MAIL_FROM_NAME"), address, nil
+}
+
+// decodeRawEmail reverses buildRawEmail's base64url encoding, for
+// transports (SMTP, dev) that need the raw RFC 2822 bytes rather than the
+// Gmail API's base64url-wrapped form.
+func decodeRawEmail(raw string) ([]byte, error) {
+	return base64.URLEncoding.DecodeString(raw)
+}
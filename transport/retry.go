@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// RetryableError marks an error as transient (HTTP 429 or 5xx) so the
+// caller can back off and try again instead of giving up. RetryAfter is
+// the delay the server asked for, if any; zero means "use your own
+// backoff schedule".
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// classifyError wraps err in a *RetryableError when the Gmail API
+// reports a rate limit or a server error.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) && (gErr.Code == http.StatusTooManyRequests || gErr.Code >= 500) {
+		return &RetryableError{Err: err, RetryAfter: retryAfterFromHeader(gErr.Header)}
+	}
+	return err
+}
+
+// retryAfterFromHeader parses a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfterFromHeader(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
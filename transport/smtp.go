@@ -0,0 +1,147 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/mail"
+)
+
+// SMTPTransport sends mail directly through a user-supplied SMTP relay,
+// upgrading the connection with STARTTLS when the server offers it. It
+// has no notion of a draft, so Draft always errors.
+type SMTPTransport struct {
+	host, port string
+	username   string
+	password   string
+}
+
+// NewSMTPTransport reads SMTP_HOST, SMTP_PORT, SMTP_USERNAME and
+// SMTP_PASSWORD from the environment.
+func NewSMTPTransport() (*SMTPTransport, error) {
+	host := os.Getenv("SMTP_HOST")
+	port := os.Getenv("SMTP_PORT")
+	username := os.Getenv("SMTP_USERNAME")
+	password := os.Getenv("SMTP_PASSWORD")
+	if host == "" || port == "" || username == "" || password == "" {
+		return nil, errors.New("SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD must all be set")
+	}
+	return &SMTPTransport{host: host, port: port, username: username, password: password}, nil
+}
+
+func (t *SMTPTransport) Draft(ctx context.Context, msg Message) error {
+	return errors.New("smtp transport has no draft concept; use Send or MAIL_TRANSPORT=gmail")
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, msg Message) error {
+	raw, err := buildRawEmail(msg, false)
+	if err != nil {
+		return fmt.Errorf("build email: %w", err)
+	}
+	data, err := decodeRawEmail(raw)
+	if err != nil {
+		return fmt.Errorf("decode email: %w", err)
+	}
+
+	addr := t.host + ":" + t.port
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	ok, _ := c.Extension("STARTTLS")
+	if ok {
+		if err := c.StartTLS(&tls.Config{ServerName: t.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	auth, err := t.auth(c, ok)
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(t.username); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	recipients := append([]mail.Recipient{msg.To}, msg.Cc...)
+	recipients = append(recipients, msg.Bcc...)
+	for _, r := range recipients {
+		if err := c.Rcpt(r.Email); err != nil {
+			return fmt.Errorf("rcpt to %s: %w", r.Email, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("write body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close body: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// auth picks the strongest mechanism the server advertises: CRAM-MD5 over
+// PLAIN over LOGIN, since CRAM-MD5 never sends the password in the clear.
+// PLAIN and LOGIN do, so starttls (whether the connection was actually
+// upgraded) must be true before either is allowed; otherwise the
+// credentials would go out over a connection anyone on the network path
+// can read.
+func (t *SMTPTransport) auth(c *smtp.Client, starttls bool) (smtp.Auth, error) {
+	ok, mechs := c.Extension("AUTH")
+	if !ok {
+		return nil, nil
+	}
+	switch {
+	case strings.Contains(mechs, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(t.username, t.password), nil
+	case !starttls:
+		return nil, fmt.Errorf("server only offers %s, which sends credentials in the clear, and STARTTLS is unavailable", mechs)
+	case strings.Contains(mechs, "PLAIN"):
+		return smtp.PlainAuth("", t.username, t.password, t.host), nil
+	case strings.Contains(mechs, "LOGIN"):
+		return &loginAuth{username: t.username, password: t.password}, nil
+	default:
+		return nil, fmt.Errorf("server offers no supported AUTH mechanism: %s", mechs)
+	}
+}
+
+// loginAuth implements the (undocumented but widely deployed) LOGIN SASL
+// mechanism, which net/smtp doesn't provide out of the box.
+type loginAuth struct {
+	username, password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(string(fromServer)) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge: %q", fromServer)
+	}
+}
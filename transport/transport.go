@@ -0,0 +1,54 @@
+// Package transport abstracts how a rendered email actually leaves this
+// program, so the Gmail-draft workflow is one option among several rather
+// than the only path through the code.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/usamashoukatcs/Gmail-OpenAI-Email/mail"
+)
+
+// Message is the transport-agnostic representation of a single email.
+// Concrete transports translate it into whatever wire format they need
+// (a raw RFC 2822 message for Gmail/SMTP, form fields for Mailgun, ...).
+type Message struct {
+	To             mail.Recipient
+	Cc             []mail.Recipient
+	Bcc            []mail.Recipient
+	Subject        string
+	HTMLBody       string
+	AttachmentPath string
+}
+
+// Transport sends or drafts a Message. Not every transport can do both:
+// SMTP and Mailgun have no notion of a "draft", so they return an error
+// from Draft instead of silently sending.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+	Draft(ctx context.Context, msg Message) error
+}
+
+// New builds the Transport selected by the MAIL_TRANSPORT environment
+// variable: "gmail" (default), "smtp", "mailgun", or "dev".
+func New() (Transport, error) {
+	kind := os.Getenv("MAIL_TRANSPORT")
+	if kind == "" {
+		kind = "gmail"
+	}
+
+	switch kind {
+	case "gmail":
+		return NewGmailTransport()
+	case "smtp":
+		return NewSMTPTransport()
+	case "mailgun":
+		return NewMailgunTransport()
+	case "dev":
+		return NewDevTransport()
+	default:
+		return nil, fmt.Errorf("unknown MAIL_TRANSPORT %q (want gmail, smtp, mailgun, or dev)", kind)
+	}
+}